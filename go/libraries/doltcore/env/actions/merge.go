@@ -25,8 +25,9 @@ import (
 )
 
 // MergeCommits is the implementation of Dolt's merge algorithm. It produces a new RootValue with merged table data, and a map containing
-// metrics about the merge including any conflicts produced by the merge.
-func MergeCommits(ctx context.Context, ddb *doltdb.DoltDB, cm1, cm2 *doltdb.Commit) (*doltdb.RootValue, map[string]*merge.MergeStats, error) {
+// metrics about the merge including any conflicts produced by the merge. opts controls how row-level conflicts are resolved; pass
+// merge.NewOptions() to leave every unresolved row in its table's MergeStats.Conflicts. Conflicts are not written into the merged tables.
+func MergeCommits(ctx context.Context, ddb *doltdb.DoltDB, cm1, cm2 *doltdb.Commit, opts merge.Options) (*doltdb.RootValue, map[string]*merge.MergeStats, error) {
 	merger, err := merge.NewMerger(ctx, cm1, cm2, ddb.ValueReadWriter())
 
 	if err != nil {
@@ -55,7 +56,7 @@ func MergeCommits(ctx context.Context, ddb *doltdb.DoltDB, cm1, cm2 *doltdb.Comm
 
 	// need to validate merges can be done on all tables before starting the actual merges.
 	for _, tblName := range tblNames {
-		mergedTable, stats, err := merger.MergeTable(ctx, tblName)
+		mergedTable, stats, err := merger.MergeTable(ctx, tblName, opts.ForTable(tblName))
 
 		if err != nil {
 			return nil, nil, err
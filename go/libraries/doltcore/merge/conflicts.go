@@ -0,0 +1,106 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/ld/dolt/go/store/diff"
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+// ResolveConflicts plans the resolution of every entry of conflicts, in path order, so
+// that `dolt conflicts resolve --path <p>` (via conflicts.Filter) can target a single
+// field inside a conflicted row instead of only whole rows. Like diff.ConflictPatch.Resolve,
+// it only plans: it returns a Patch of the resolved fields and a ConflictPatch of
+// whatever the resolver declined, without writing either back into a table. Use
+// ApplyConflictResolution to do that.
+func ResolveConflicts(ctx context.Context, conflicts diff.ConflictPatch, resolver diff.ConflictResolver) (diff.Patch, diff.ConflictPatch, error) {
+	return conflicts.Resolve(ctx, resolver)
+}
+
+// ResolveConflictsAtPath is ResolveConflicts narrowed to the conflicts at or under path,
+// the building block for `dolt conflicts resolve --path <p>`.
+func ResolveConflictsAtPath(ctx context.Context, format *types.Format, conflicts diff.ConflictPatch, path types.Path, resolver diff.ConflictResolver) (diff.Patch, diff.ConflictPatch, error) {
+	return ResolveConflicts(ctx, conflicts.Filter(format, path), resolver)
+}
+
+// ApplyConflictResolution is the root-mutating half of `dolt conflicts resolve --path
+// <p>`: it resolves conflicts against tbl's row data using resolver, writes whatever it
+// resolves back into the rows, and returns the updated table alongside whatever
+// conflicts remain. diff.ConflictPatch.Resolve can't do this step itself because the
+// diff package has no notion of a doltdb.Table to write into.
+//
+// TODO: no `dolt conflicts resolve --path <p>` command calls this yet -- this checkout
+// has no cmd/ package for it to live in. Until that wiring lands, this is only reachable
+// from a caller written directly against this package.
+//
+// conflicts must be whole-row conflicts recorded the way mergeRow records them, a single
+// types.IndexPath keyed by the row's primary key; a conflict with any other shape is
+// skipped rather than guessed at.
+func ApplyConflictResolution(ctx context.Context, tbl *doltdb.Table, conflicts diff.ConflictPatch, resolver diff.ConflictResolver) (*doltdb.Table, diff.ConflictPatch, error) {
+	resolved, remaining, err := ResolveConflicts(ctx, conflicts, resolver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rowData, err := tbl.GetRowData(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edit := rowData.Edit()
+
+	for _, d := range resolved {
+		key, ok := conflictRowKey(d.Path)
+		if !ok {
+			continue
+		}
+
+		if d.NewValue == nil {
+			edit.Remove(key)
+		} else {
+			edit.Set(key, d.NewValue)
+		}
+	}
+
+	mergedData, err := edit.Map(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newTbl, err := tbl.UpdateRows(ctx, mergedData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newTbl, remaining, nil
+}
+
+// conflictRowKey recovers the primary key from a path recorded by mergeRow, which only
+// ever records whole-row conflicts as a single-element types.IndexPath.
+func conflictRowKey(path types.Path) (types.Value, bool) {
+	if len(path) != 1 {
+		return nil, false
+	}
+
+	idx, ok := path[0].(types.IndexPath)
+	if !ok {
+		return nil, false
+	}
+
+	return idx.Index, true
+}
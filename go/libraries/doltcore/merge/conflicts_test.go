@@ -0,0 +1,55 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+func TestConflictRowKey(t *testing.T) {
+	t.Run("single IndexPath recovers the key", func(t *testing.T) {
+		path := types.Path{types.IndexPath{Index: types.String("k")}}
+
+		key, ok := conflictRowKey(path)
+		if !ok || key != types.String("k") {
+			t.Errorf("expected key %v, got %v ok=%v", types.String("k"), key, ok)
+		}
+	})
+
+	t.Run("a field-level path is not a row key", func(t *testing.T) {
+		path := types.Path{types.FieldPath{Name: "col"}}
+
+		if _, ok := conflictRowKey(path); ok {
+			t.Error("expected ok=false for a non-IndexPath")
+		}
+	})
+
+	t.Run("an empty or multi-part path is not a row key", func(t *testing.T) {
+		if _, ok := conflictRowKey(nil); ok {
+			t.Error("expected ok=false for an empty path")
+		}
+
+		path := types.Path{types.IndexPath{Index: types.String("k")}, types.FieldPath{Name: "col"}}
+		if _, ok := conflictRowKey(path); ok {
+			t.Error("expected ok=false for a multi-part path")
+		}
+	})
+
+	// ApplyConflictResolution itself needs a real doltdb.Table backed by a types.Map,
+	// which this package has no fixtures for building (same gap merger_test.go notes for
+	// the StrategyUnion success path); conflictRowKey is the part of it that's pure.
+}
@@ -0,0 +1,373 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/dolt/go/libraries/doltcore/doltdb"
+	"github.com/liquidata-inc/ld/dolt/go/store/diff"
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+// TableOperation describes what MergeTable did to a table as a whole. The row-level
+// counts live on MergeStats alongside it.
+type TableOperation int
+
+const (
+	// TableModified means both sides had the table and MergeTable merged their rows.
+	TableModified TableOperation = iota
+	// TableAdded means only cm2 had the table.
+	TableAdded
+	// TableRemoved means the table existed at the common ancestor but is gone from one
+	// side, so it's removed from the merged root entirely.
+	TableRemoved
+	// TableUnmodified means only cm1 had the table, so it passes through untouched.
+	TableUnmodified
+)
+
+// MergeStats summarizes a single table's merge.
+type MergeStats struct {
+	Operation     TableOperation
+	Adds          int
+	Deletes       int
+	Modifications int
+	// AutoResolved counts rows changed on both sides that a Resolver or a non-Fail
+	// Strategy resolved, rather than writing to Conflicts.
+	AutoResolved int
+	// Conflicts holds the rows changed on both sides that neither a Resolver nor the
+	// table's Strategy could resolve, keyed by primary key value.
+	Conflicts diff.ConflictPatch
+}
+
+// Merger merges the tables of cm2 into cm1, against their common ancestor.
+type Merger struct {
+	vrw types.ValueReadWriter
+	cm1 *doltdb.Commit
+	cm2 *doltdb.Commit
+}
+
+// NewMerger returns a Merger that merges cm2's changes into cm1, reading and writing
+// row data through vrw.
+func NewMerger(ctx context.Context, cm1, cm2 *doltdb.Commit, vrw types.ValueReadWriter) (*Merger, error) {
+	return &Merger{vrw: vrw, cm1: cm1, cm2: cm2}, nil
+}
+
+// MergeTable merges the rows of tblName as it exists in cm1 and cm2 against their
+// common ancestor. It invokes opts.Resolver (falling back to opts.Strategy) for any row
+// changed on both sides to different values, only leaving a row in the returned
+// MergeStats.Conflicts when neither resolves it. It returns a nil table if the table
+// doesn't exist on either side, or if cm2 no longer has a table that existed at the
+// common ancestor (reported as TableRemoved).
+func (m *Merger) MergeTable(ctx context.Context, tblName string, opts TableOptions) (*doltdb.Table, *MergeStats, error) {
+	ourRoot, err := m.cm1.GetRootValue()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	theirRoot, err := m.cm2.GetRootValue()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ourTbl, ourOK, err := ourRoot.GetTable(ctx, tblName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	theirTbl, theirOK, err := theirRoot.GetTable(ctx, tblName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ourOK && !theirOK {
+		return nil, nil, nil
+	}
+
+	if !theirOK {
+		_, ancOK, err := m.ancestorTable(ctx, tblName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ancOK {
+			return ourTbl, &MergeStats{Operation: TableUnmodified}, nil
+		}
+
+		return nil, &MergeStats{Operation: TableRemoved}, nil
+	}
+
+	if !ourOK {
+		return theirTbl, &MergeStats{Operation: TableAdded}, nil
+	}
+
+	ancTbl, ancOK, err := m.ancestorTable(ctx, tblName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseData, err := emptyMapIfMissing(ctx, m.vrw, ancTbl, ancOK)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ourData, err := ourTbl.GetRowData(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	theirData, err := theirTbl.GetRowData(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergedData, stats, err := mergeRows(ctx, baseData, ourData, theirData, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergedTbl, err := ourTbl.UpdateRows(ctx, mergedData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats.Operation = TableModified
+
+	return mergedTbl, stats, nil
+}
+
+func (m *Merger) ancestorTable(ctx context.Context, tblName string) (*doltdb.Table, bool, error) {
+	anc, err := doltdb.GetCommonAncestor(ctx, m.cm1, m.cm2)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ancRoot, err := anc.GetRootValue()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return ancRoot.GetTable(ctx, tblName)
+}
+
+func emptyMapIfMissing(ctx context.Context, vrw types.ValueReadWriter, tbl *doltdb.Table, ok bool) (types.Map, error) {
+	if !ok {
+		return types.NewMap(ctx, vrw)
+	}
+
+	return tbl.GetRowData(ctx)
+}
+
+// mergeRows walks base and theirs together key by key (both types.Maps are kept in key
+// order, so this is a single merge-join), looks each key up in ours, and decides, for
+// each key, what the merged row should be. Keys found only in ours are never visited;
+// they stay as they are, since the merged rows start from ours:
+//   - unchanged on both sides: keep it
+//   - changed on exactly one side: take that side's value (including a delete)
+//   - changed on both sides to the same value: keep it, no conflict
+//   - changed on both sides to different values: ask opts.Resolver, then opts.Strategy;
+//     only write it to the returned Conflicts if neither resolves it
+func mergeRows(ctx context.Context, base, ours, theirs types.Map, opts TableOptions) (types.Map, *MergeStats, error) {
+	stats := &MergeStats{}
+	edit := ours.Edit()
+
+	baseItr, err := base.Iterator(ctx)
+	if err != nil {
+		return types.Map{}, nil, err
+	}
+
+	theirsItr, err := theirs.Iterator(ctx)
+	if err != nil {
+		return types.Map{}, nil, err
+	}
+
+	baseKey, baseVal, err := baseItr.Next(ctx)
+	if err != nil {
+		return types.Map{}, nil, err
+	}
+
+	theirKey, theirVal, err := theirsItr.Next(ctx)
+	if err != nil {
+		return types.Map{}, nil, err
+	}
+
+	for baseKey != nil || theirKey != nil {
+		var key types.Value
+		var baseRow, theirRow types.Value
+
+		switch {
+		case baseKey == nil:
+			key, theirRow = theirKey, theirVal
+			theirKey, theirVal, err = theirsItr.Next(ctx)
+		case theirKey == nil:
+			key, baseRow = baseKey, baseVal
+			baseKey, baseVal, err = baseItr.Next(ctx)
+		case baseKey.Equals(theirKey):
+			key, baseRow, theirRow = baseKey, baseVal, theirVal
+			baseKey, baseVal, err = baseItr.Next(ctx)
+			if err == nil {
+				theirKey, theirVal, err = theirsItr.Next(ctx)
+			}
+		// Primary keys are scalar values (string, int, ...), whose ordering doesn't
+		// depend on the storage format, so a nil *types.Format is safe here.
+		case baseKey.Less(nil, theirKey):
+			key, baseRow = baseKey, baseVal
+			baseKey, baseVal, err = baseItr.Next(ctx)
+		default:
+			key, theirRow = theirKey, theirVal
+			theirKey, theirVal, err = theirsItr.Next(ctx)
+		}
+
+		if err != nil {
+			return types.Map{}, nil, err
+		}
+
+		ourRow, ourHas, err := ours.MaybeGet(ctx, key)
+		if err != nil {
+			return types.Map{}, nil, err
+		}
+		if !ourHas {
+			ourRow = nil
+		}
+
+		if err := mergeRow(ctx, edit, stats, opts, key, baseRow, ourRow, theirRow); err != nil {
+			return types.Map{}, nil, err
+		}
+	}
+
+	mergedData, err := edit.Map(ctx)
+	if err != nil {
+		return types.Map{}, nil, err
+	}
+
+	return mergedData, stats, nil
+}
+
+// rowEditor is the subset of *types.MapEditor that mergeRow needs to apply a merge
+// decision. mergeRow takes this narrow interface, rather than *types.MapEditor directly,
+// so its branching logic can be exercised in merger_test.go against a fake.
+type rowEditor interface {
+	Set(key, value types.Value)
+	Remove(key types.Value)
+}
+
+// mergeRow applies the merge decision for a single key to edit, given the row's value
+// (nil if absent) at the base, ours, and theirs.
+func mergeRow(ctx context.Context, edit rowEditor, stats *MergeStats, opts TableOptions, key, base, ours, theirs types.Value) error {
+	ourChanged := !valuesEqual(base, ours)
+	theirChanged := !valuesEqual(base, theirs)
+
+	switch {
+	case !ourChanged && !theirChanged:
+		return nil
+
+	case ourChanged && !theirChanged:
+		return applyRow(edit, stats, key, ours)
+
+	case !ourChanged && theirChanged:
+		return applyRow(edit, stats, key, theirs)
+
+	case valuesEqual(ours, theirs):
+		// both sides made the identical change; no conflict.
+		return applyRow(edit, stats, key, ours)
+	}
+
+	if opts.Resolver != nil {
+		resolved, ok, err := opts.Resolver(ctx, key, base, ours, theirs)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			stats.AutoResolved++
+			return applyRow(edit, stats, key, resolved)
+		}
+	}
+
+	switch opts.Strategy {
+	case StrategyOurs:
+		stats.AutoResolved++
+		return applyRow(edit, stats, key, ours)
+
+	case StrategyTheirs:
+		stats.AutoResolved++
+		return applyRow(edit, stats, key, theirs)
+
+	case StrategyUnion:
+		unioned, ok, err := unionValues(ctx, ours, theirs)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			stats.AutoResolved++
+			return applyRow(edit, stats, key, unioned)
+		}
+	}
+
+	stats.Conflicts = append(stats.Conflicts, diff.ConflictDifference{
+		Path:   types.Path{types.IndexPath{Index: key}},
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+	})
+
+	return nil
+}
+
+func applyRow(edit rowEditor, stats *MergeStats, key, value types.Value) error {
+	if value == nil {
+		stats.Deletes++
+		edit.Remove(key)
+		return nil
+	}
+
+	stats.Modifications++
+	edit.Set(key, value)
+
+	return nil
+}
+
+// unionValues unions two rows for StrategyUnion when both are types.Set values. For any
+// other pair (scalars, Lists, Maps, or mixed kinds) it returns false, so callers fall
+// back to leaving the row as a conflict.
+func unionValues(ctx context.Context, ours, theirs types.Value) (types.Value, bool, error) {
+	ourSet, ourOK := ours.(types.Set)
+	theirSet, theirOK := theirs.(types.Set)
+
+	if !ourOK || !theirOK {
+		return nil, false, nil
+	}
+
+	edited, err := ourSet.Edit().InsertAll(ctx, theirSet)
+	if err != nil {
+		return nil, false, err
+	}
+
+	unioned, err := edited.Set(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return unioned, true, nil
+}
+
+func valuesEqual(a, b types.Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equals(b)
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+// fakeEditor is a rowEditor that records the Set/Remove calls mergeRow makes, so tests
+// can assert on the merge decision without a real types.MapEditor.
+type fakeEditor struct {
+	set    map[types.Value]types.Value
+	remove []types.Value
+}
+
+func newFakeEditor() *fakeEditor {
+	return &fakeEditor{set: map[types.Value]types.Value{}}
+}
+
+func (f *fakeEditor) Set(key, value types.Value) { f.set[key] = value }
+func (f *fakeEditor) Remove(key types.Value)     { f.remove = append(f.remove, key) }
+
+func TestMergeRow(t *testing.T) {
+	ctx := context.Background()
+	key := types.String("k")
+
+	t.Run("unchanged on both sides is left alone", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), types.String("base"), types.String("base"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edit.set) != 0 || len(edit.remove) != 0 {
+			t.Errorf("expected no edits, got set=%v remove=%v", edit.set, edit.remove)
+		}
+		if stats.Modifications != 0 || stats.AutoResolved != 0 || len(stats.Conflicts) != 0 {
+			t.Errorf("expected no stats changes, got %+v", stats)
+		}
+	})
+
+	t.Run("changed on our side only takes ours", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), types.String("ours"), types.String("base"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("ours") {
+			t.Errorf("expected ours to win, got %v", edit.set[key])
+		}
+		if stats.Modifications != 1 {
+			t.Errorf("expected 1 modification, got %d", stats.Modifications)
+		}
+	})
+
+	t.Run("changed on their side only takes theirs", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), types.String("base"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("theirs") {
+			t.Errorf("expected theirs to win, got %v", edit.set[key])
+		}
+		if stats.Modifications != 1 {
+			t.Errorf("expected 1 modification, got %d", stats.Modifications)
+		}
+	})
+
+	t.Run("identical change on both sides is not a conflict", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), types.String("same"), types.String("same"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("same") {
+			t.Errorf("expected the shared value to be written, got %v", edit.set[key])
+		}
+		if len(stats.Conflicts) != 0 {
+			t.Errorf("expected no conflict, got %+v", stats.Conflicts)
+		}
+	})
+
+	t.Run("resolver hit wins over strategy", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+		opts := TableOptions{
+			Strategy: StrategyTheirs,
+			Resolver: func(ctx context.Context, key, base, ours, theirs types.Value) (types.Value, bool, error) {
+				return types.String("resolved"), true, nil
+			},
+		}
+
+		err := mergeRow(ctx, edit, stats, opts, key, types.String("base"), types.String("ours"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("resolved") {
+			t.Errorf("expected the resolver's value, got %v", edit.set[key])
+		}
+		if stats.AutoResolved != 1 {
+			t.Errorf("expected 1 auto-resolved row, got %d", stats.AutoResolved)
+		}
+	})
+
+	t.Run("resolver miss falls back to StrategyOurs", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+		opts := TableOptions{
+			Strategy: StrategyOurs,
+			Resolver: func(ctx context.Context, key, base, ours, theirs types.Value) (types.Value, bool, error) {
+				return nil, false, nil
+			},
+		}
+
+		err := mergeRow(ctx, edit, stats, opts, key, types.String("base"), types.String("ours"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("ours") {
+			t.Errorf("expected ours to win, got %v", edit.set[key])
+		}
+		if stats.AutoResolved != 1 {
+			t.Errorf("expected 1 auto-resolved row, got %d", stats.AutoResolved)
+		}
+	})
+
+	t.Run("no resolver falls back to StrategyTheirs", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+		opts := TableOptions{Strategy: StrategyTheirs}
+
+		err := mergeRow(ctx, edit, stats, opts, key, types.String("base"), types.String("ours"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit.set[key] != types.String("theirs") {
+			t.Errorf("expected theirs to win, got %v", edit.set[key])
+		}
+		if stats.AutoResolved != 1 {
+			t.Errorf("expected 1 auto-resolved row, got %d", stats.AutoResolved)
+		}
+	})
+
+	t.Run("StrategyUnion falls back to conflict for non-Set values", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+		opts := TableOptions{Strategy: StrategyUnion}
+
+		err := mergeRow(ctx, edit, stats, opts, key, types.String("base"), types.String("ours"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edit.set) != 0 {
+			t.Errorf("expected no edit to be applied, got %v", edit.set)
+		}
+		if len(stats.Conflicts) != 1 {
+			t.Fatalf("expected 1 conflict, got %d", len(stats.Conflicts))
+		}
+		if stats.Conflicts[0].Ours != types.String("ours") || stats.Conflicts[0].Theirs != types.String("theirs") {
+			t.Errorf("expected the conflict to carry both sides' values, got %+v", stats.Conflicts[0])
+		}
+
+		// unionValues only has a defined result for two types.Set values (see its own
+		// doc comment); exercising the success path needs real, vrw-backed types.Set
+		// fixtures that this package doesn't have, the same gap patch_test.go notes for
+		// resolvePreImage.
+	})
+
+	t.Run("StrategyFail with no resolver records a conflict", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), types.String("ours"), types.String("theirs"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edit.set) != 0 || len(edit.remove) != 0 {
+			t.Errorf("expected no edits, got set=%v remove=%v", edit.set, edit.remove)
+		}
+		if len(stats.Conflicts) != 1 {
+			t.Fatalf("expected 1 conflict, got %d", len(stats.Conflicts))
+		}
+	})
+
+	t.Run("a changed-to-deleted row removes the key", func(t *testing.T) {
+		edit := newFakeEditor()
+		stats := &MergeStats{}
+
+		err := mergeRow(ctx, edit, stats, TableOptions{}, key, types.String("base"), nil, types.String("base"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edit.remove) != 1 || edit.remove[0] != key {
+			t.Errorf("expected the key to be removed, got %v", edit.remove)
+		}
+		if stats.Deletes != 1 {
+			t.Errorf("expected 1 delete, got %d", stats.Deletes)
+		}
+	})
+}
+
+func TestValuesEqual(t *testing.T) {
+	if !valuesEqual(nil, nil) {
+		t.Error("expected nil == nil")
+	}
+	if valuesEqual(nil, types.String("a")) || valuesEqual(types.String("a"), nil) {
+		t.Error("expected nil to never equal a value")
+	}
+	if !valuesEqual(types.String("a"), types.String("a")) {
+		t.Error("expected equal values to compare equal")
+	}
+	if valuesEqual(types.String("a"), types.String("b")) {
+		t.Error("expected different values to compare unequal")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+// Strategy picks how a row-level conflict is resolved when both sides of a merge change
+// the same row and no Resolver is registered for the table, or the registered Resolver
+// declines to resolve it.
+type Strategy int
+
+const (
+	// StrategyFail records the row in MergeStats.Conflicts and keeps cm1's value for it
+	// in the merged rows. MergeTable does not write conflicts into the table itself.
+	StrategyFail Strategy = iota
+	// StrategyOurs keeps the value from the merge destination (cm1).
+	StrategyOurs
+	// StrategyTheirs keeps the value from the merge source (cm2).
+	StrategyTheirs
+	// StrategyUnion unions the two sides rather than picking one. It only applies when
+	// both sides' rows are types.Set values; MergeTable falls back to StrategyFail for
+	// any other row.
+	StrategyUnion
+)
+
+// Resolver resolves a single row that changed on both sides of a merge. It returns the
+// resolved value and true if it resolved the conflict, or false to fall back to the
+// table's Strategy.
+type Resolver func(ctx context.Context, key, base, ours, theirs types.Value) (types.Value, bool, error)
+
+// TableOptions controls how MergeTable resolves conflicts for a single table.
+type TableOptions struct {
+	// Strategy is the fallback used when Resolver is nil or returns false.
+	Strategy Strategy
+	// Resolver, if non-nil, is invoked for every row changed on both sides of the merge
+	// to different values, before Strategy is consulted. A row both sides changed to the
+	// same value is merged without it.
+	Resolver Resolver
+}
+
+// Options controls how MergeCommits resolves row-level conflicts across all of the
+// tables being merged.
+//
+// TODO: neither `dolt merge --strategy=` nor a SQL merge function constructs an Options
+// yet -- this checkout has no cmd/ or sqle package for them to live in. Until that
+// wiring lands, Options can only be set by a caller written directly against this
+// package, so callers get StrategyFail (via NewOptions) unless they build one by hand.
+type Options struct {
+	// Strategy is the default used for any table without an entry in TableOptions.
+	Strategy Strategy
+	// TableOptions maps table name to a per-table override. A table present here with a
+	// nil Resolver still uses its own Strategy override, ignoring the top-level one.
+	TableOptions map[string]TableOptions
+}
+
+// NewOptions returns an Options with StrategyFail and no per-table overrides, equivalent
+// to MergeTable's original behavior.
+func NewOptions() Options {
+	return Options{Strategy: StrategyFail, TableOptions: map[string]TableOptions{}}
+}
+
+// ForTable returns the effective TableOptions for tblName, falling back to the
+// top-level Strategy when the table has no override.
+func (o Options) ForTable(tblName string) TableOptions {
+	if tblOpts, ok := o.TableOptions[tblName]; ok {
+		return tblOpts
+	}
+
+	return TableOptions{Strategy: o.Strategy}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderRFC4180(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     ReaderOptions
+		expected [][]string
+	}{
+		{
+			name:  "simple rows",
+			input: "one,two,three\nfour,five,six\n",
+			opts:  ReaderOptions{},
+			expected: [][]string{
+				{"one", "two", "three"},
+				{"four", "five", "six"},
+			},
+		},
+		{
+			name:  "crlf line endings",
+			input: "one,two\r\nthree,four\r\n",
+			opts:  ReaderOptions{},
+			expected: [][]string{
+				{"one", "two"},
+				{"three", "four"},
+			},
+		},
+		{
+			name:  "embedded newline inside quoted field",
+			input: "one,\"two\nstill two\",three\nfour,five,six\n",
+			opts:  ReaderOptions{},
+			expected: [][]string{
+				{"one", "two\nstill two", "three"},
+				{"four", "five", "six"},
+			},
+		},
+		{
+			name:  "embedded crlf inside quoted field",
+			input: "one,\"two\r\nstill two\",three\r\n",
+			opts:  ReaderOptions{},
+			expected: [][]string{
+				{"one", "two\r\nstill two", "three"},
+			},
+		},
+		{
+			name:  "utf8 BOM is stripped",
+			input: utf8BOM + "one,two,three\n",
+			opts:  ReaderOptions{},
+			expected: [][]string{
+				{"one", "two", "three"},
+			},
+		},
+		{
+			name:  "tab delimited dialect",
+			input: "one\ttwo\tthree\n",
+			opts:  ReaderOptions{Delim: '\t'},
+			expected: [][]string{
+				{"one", "two", "three"},
+			},
+		},
+		{
+			name:  "whitespace trimmed outside quotes only",
+			input: "one,  two  ,\"  three  \"\n",
+			opts:  ReaderOptions{TrimWhitespace: true},
+			expected: [][]string{
+				{"one", "two", "  three  "},
+			},
+		},
+		{
+			name:  "backslash-escaped dialect",
+			input: `one,"two\"three",four` + "\n" + `five,"six\\seven",eight` + "\n",
+			opts:  ReaderOptions{Escape: '\\'},
+			expected: [][]string{
+				{"one", `two"three`, "four"},
+				{"five", `six\seven`, "eight"},
+			},
+		},
+		{
+			name:  "legacy dialect does not span embedded newlines",
+			input: "one,\"two\nstill two\",three\n",
+			opts:  ReaderOptions{Dialect: DialectLegacy},
+			expected: [][]string{
+				// csvSplitLine only sees "one,\"two" on the first line: the opening
+				// quote is consumed but never closed, so "two" comes out unquoted-
+				// looking but is still marked quoted (no trim) and the row ends there.
+				{"one", "two"},
+				// The second line, "still two\",three", sees the stray closing quote
+				// mid-field: it toggles back into "quoted" state, so the comma that
+				// follows is treated as literal text rather than a delimiter.
+				{"still two,three"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewReader(bufio.NewReader(strings.NewReader(test.input)), test.opts)
+
+			var rows [][]string
+			for {
+				row, err := r.ReadRow()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error reading row: %v", err)
+				}
+				rows = append(rows, row)
+			}
+
+			if len(rows) != len(test.expected) {
+				t.Fatalf("expected %d rows, got %d: %v", len(test.expected), len(rows), rows)
+			}
+
+			for i, row := range rows {
+				expectedRow := test.expected[i]
+				if len(row) != len(expectedRow) {
+					t.Fatalf("row %d: expected %v, got %v", i, expectedRow, row)
+				}
+				for j, field := range row {
+					if field != expectedRow[j] {
+						t.Errorf("row %d field %d: expected %q, got %q", i, j, expectedRow[j], field)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csv
+
+import "strings"
+
+// csvSplitLine splits a single line of delimited text into fields. It predates the
+// streaming, RFC 4180-compliant Reader in reader.go and only understands a single line
+// at a time, so a quoted field can never contain an embedded newline. It is kept around
+// as the implementation of DialectLegacy for callers that rely on its exact quirks.
+//
+// When escapeQuotes is true, a `"` toggles whether the reader is inside a quoted field,
+// and a doubled `""` (in or out of a quoted field) collapses to a single literal `"`.
+// Fields that are never quoted have leading and trailing whitespace trimmed; fields that
+// pass through a quoted region at any point are returned byte-for-byte.
+func csvSplitLine(line string, delim rune, escapeQuotes bool) []string {
+	if line == "" {
+		return []string{}
+	}
+
+	runes := []rune(line)
+	n := len(runes)
+
+	var tokens []string
+	var sb strings.Builder
+	inQuotes := false
+	wasQuoted := false
+
+	flush := func() {
+		field := sb.String()
+		if !wasQuoted {
+			field = strings.TrimSpace(field)
+		}
+		tokens = append(tokens, field)
+		sb.Reset()
+		wasQuoted = false
+	}
+
+	for i := 0; i < n; i++ {
+		c := runes[i]
+
+		if escapeQuotes && c == '"' {
+			if i+1 < n && runes[i+1] == '"' {
+				sb.WriteRune('"')
+				i++
+				continue
+			}
+
+			inQuotes = !inQuotes
+			wasQuoted = true
+			continue
+		}
+
+		if c == delim && !inQuotes {
+			flush()
+			continue
+		}
+
+		sb.WriteRune(c)
+	}
+
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,271 @@
+// Copyright 2019 Liquidata, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package csv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dialect selects the set of parsing conventions a Reader uses to split rows into fields.
+type Dialect int
+
+const (
+	// DialectRFC4180 reads RFC 4180-compliant delimited text: fields may be quoted, a
+	// quoted field may contain embedded newlines and delimiters, and a doubled quote
+	// escapes a literal quote. The Delim, Quote, and Escape options make this dialect
+	// flexible enough to cover TSV, pipe-delimited, and Excel-style exports.
+	DialectRFC4180 Dialect = iota
+
+	// DialectLegacy reproduces the original, line-oriented csvSplitLine behavior. It
+	// cannot parse a quoted field that spans more than one line.
+	DialectLegacy
+)
+
+const utf8BOM = "\xef\xbb\xbf"
+
+// ReaderOptions configures how a Reader splits rows of delimited text into fields.
+type ReaderOptions struct {
+	// Dialect selects the parsing strategy. Defaults to DialectRFC4180.
+	Dialect Dialect
+
+	// Delim is the field delimiter. Defaults to ',' when unset.
+	Delim rune
+
+	// Quote is the character used to quote a field. Defaults to '"' when unset.
+	Quote rune
+
+	// Escape is the character used to escape a Quote inside a quoted field. Defaults to
+	// Quote itself, i.e. RFC 4180's `""` escaping. Set it to something else (e.g. '\\')
+	// to support backslash-escaped dialects.
+	Escape rune
+
+	// TrimWhitespace, when true, trims leading and trailing whitespace from fields that
+	// were never quoted. Whitespace inside a quoted field is always preserved.
+	TrimWhitespace bool
+}
+
+func (opts ReaderOptions) withDefaults() ReaderOptions {
+	if opts.Delim == 0 {
+		opts.Delim = ','
+	}
+	if opts.Quote == 0 {
+		opts.Quote = '"'
+	}
+	if opts.Escape == 0 {
+		opts.Escape = opts.Quote
+	}
+	return opts
+}
+
+// Reader reads rows of delimited text from a bufio.Reader, one row at a time. Unlike
+// csvSplitLine, a DialectRFC4180 Reader tracks quote state across line boundaries, so a
+// quoted field may contain embedded `\n` or `\r\n` sequences.
+type Reader struct {
+	br          *bufio.Reader
+	opts        ReaderOptions
+	strippedBOM bool
+}
+
+// NewReader returns a Reader that reads rows from br using opts. Zero-valued fields of
+// opts fall back to RFC 4180 defaults (Dialect: DialectRFC4180, Delim: ',', Quote: '"',
+// Escape: '"').
+func NewReader(br *bufio.Reader, opts ReaderOptions) *Reader {
+	return &Reader{br: br, opts: opts.withDefaults()}
+}
+
+// ReadRow reads and returns the next row of fields. It returns io.EOF (and a nil row)
+// once all input has been consumed.
+func (r *Reader) ReadRow() ([]string, error) {
+	if !r.strippedBOM {
+		r.strippedBOM = true
+
+		if err := r.stripBOM(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.opts.Dialect == DialectLegacy {
+		return r.readLegacyRow()
+	}
+
+	return r.readRFC4180Row()
+}
+
+// stripBOM discards a leading UTF-8 byte order mark, if present. It is a no-op for any
+// other input, including inputs too short to contain a BOM.
+func (r *Reader) stripBOM() error {
+	bom := []byte(utf8BOM)
+
+	peeked, err := r.br.Peek(len(bom))
+	if err != nil {
+		// Not enough bytes left for a BOM (or none at all) is not an error here; the
+		// regular read path will surface io.EOF when appropriate.
+		return nil
+	}
+
+	if string(peeked) == utf8BOM {
+		_, err = r.br.Discard(len(bom))
+		return err
+	}
+
+	return nil
+}
+
+// readLegacyRow reads a single `\n`-terminated line (tolerating a trailing `\r`) and
+// splits it with csvSplitLine. It cannot see across line boundaries, so a quoted field
+// containing an embedded newline will be split incorrectly, matching legacy behavior.
+func (r *Reader) readLegacyRow() ([]string, error) {
+	line, err := r.br.ReadString('\n')
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	}
+
+	line = strings.TrimRight(line, "\r\n")
+
+	return csvSplitLine(line, r.opts.Delim, true), nil
+}
+
+// readRFC4180Row reads a single row, honoring quoted fields that span multiple lines.
+func (r *Reader) readRFC4180Row() ([]string, error) {
+	var fields []string
+	var sb strings.Builder
+
+	inQuotes := false
+	quotedField := false
+	fieldStarted := false
+	sawAnyRune := false
+
+	flush := func() {
+		field := sb.String()
+		if r.opts.TrimWhitespace && !quotedField {
+			field = strings.TrimSpace(field)
+		}
+		fields = append(fields, field)
+		sb.Reset()
+		quotedField = false
+		fieldStarted = false
+	}
+
+	for {
+		c, _, err := r.br.ReadRune()
+
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+
+			if inQuotes {
+				return nil, fmt.Errorf("csv: unexpected EOF inside quoted field")
+			}
+
+			if !sawAnyRune {
+				return nil, io.EOF
+			}
+
+			flush()
+
+			return fields, nil
+		}
+
+		sawAnyRune = true
+
+		switch {
+		case inQuotes:
+			if r.opts.Escape != r.opts.Quote && c == r.opts.Escape {
+				next, _, nerr := r.br.ReadRune()
+
+				if nerr != nil {
+					if nerr == io.EOF {
+						return nil, fmt.Errorf("csv: unexpected EOF after escape character inside quoted field")
+					}
+					return nil, nerr
+				}
+
+				if next == r.opts.Quote || next == r.opts.Escape {
+					sb.WriteRune(next)
+					continue
+				}
+
+				// The escape character wasn't followed by a quote or another escape
+				// character, so it wasn't escaping anything; keep both runes as-is.
+				sb.WriteRune(c)
+				sb.WriteRune(next)
+				continue
+			}
+
+			if c == r.opts.Quote {
+				next, _, nerr := r.br.ReadRune()
+
+				if nerr == nil && next == r.opts.Quote {
+					sb.WriteRune(r.opts.Quote)
+					continue
+				}
+
+				if nerr == nil {
+					if uerr := r.br.UnreadRune(); uerr != nil {
+						return nil, uerr
+					}
+				} else if nerr != io.EOF {
+					return nil, nerr
+				}
+
+				inQuotes = false
+				continue
+			}
+
+			sb.WriteRune(c)
+
+		case c == r.opts.Quote && !fieldStarted:
+			inQuotes = true
+			quotedField = true
+			fieldStarted = true
+
+		case c == r.opts.Delim:
+			flush()
+
+		case c == '\r':
+			next, _, nerr := r.br.ReadRune()
+
+			if nerr == nil && next != '\n' {
+				if uerr := r.br.UnreadRune(); uerr != nil {
+					return nil, uerr
+				}
+			} else if nerr != nil && nerr != io.EOF {
+				return nil, nerr
+			}
+
+			flush()
+
+			return fields, nil
+
+		case c == '\n':
+			flush()
+
+			return fields, nil
+
+		default:
+			sb.WriteRune(c)
+			fieldStarted = true
+		}
+	}
+}
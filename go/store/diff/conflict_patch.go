@@ -0,0 +1,118 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"context"
+	"sort"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+// ConflictDifference is a single three-way conflict at a path: base, ours, and theirs
+// all disagree on the value at Path (a FieldPath, IndexPath, or HashIndexPath), so the
+// merge could not pick a value automatically.
+type ConflictDifference struct {
+	Path   types.Path
+	Base   types.Value
+	Ours   types.Value
+	Theirs types.Value
+}
+
+// ConflictPatch is a sorted list of ConflictDifference, using the same path ordering as
+// Patch (see pathIsLess). Keeping conflicts in this order lets two ConflictPatches (or a
+// ConflictPatch and a Patch) be merged, filtered, or re-serialized without re-walking
+// the rows they came from.
+type ConflictPatch []ConflictDifference
+
+// conflictPatchSort sorts a ConflictPatch into Patch's standard path order.
+type conflictPatchSort struct {
+	patch  ConflictPatch
+	format *types.Format
+}
+
+func (cs conflictPatchSort) Len() int      { return len(cs.patch) }
+func (cs conflictPatchSort) Swap(i, j int) { cs.patch[i], cs.patch[j] = cs.patch[j], cs.patch[i] }
+func (cs conflictPatchSort) Less(i, j int) bool {
+	return pathIsLess(cs.format, cs.patch[i].Path, cs.patch[j].Path)
+}
+
+// Sort orders cp in place using the same path ordering as PatchSort.
+func (cp ConflictPatch) Sort(format *types.Format) {
+	sort.Stable(conflictPatchSort{cp, format})
+}
+
+// ConflictResolver resolves a single field-level conflict. It returns the resolved value
+// and true if it was able to resolve the conflict, or false to leave it as a conflict.
+type ConflictResolver func(ctx context.Context, path types.Path, base, ours, theirs types.Value) (types.Value, bool, error)
+
+// Resolve walks cp once, in its sorted order, invoking resolver on every entry. It only
+// plans the resolution: entries the resolver resolves come back as a Patch of
+// DiffChangeModified Differences (NewValue set to the resolved value, OldValue set to
+// Theirs); entries the resolver declines (or that have a nil resolver) come back
+// untouched in the remaining ConflictPatch, for the caller to keep surfacing as
+// conflicts. This package has no notion of a doltdb.Table or RootValue to write the
+// resolved Patch into, so actually mutating one is the caller's job; see
+// merge.ApplyConflictResolution for the root-mutating half of `dolt conflicts resolve
+// --path <p>`.
+//
+// cp must already be sorted (e.g. via Sort); Resolve does not re-sort it, so that callers
+// who have already merged several ConflictPatches together don't pay for it twice.
+func (cp ConflictPatch) Resolve(ctx context.Context, resolver ConflictResolver) (Patch, ConflictPatch, error) {
+	if resolver == nil {
+		return nil, cp, nil
+	}
+
+	var resolved Patch
+	var remaining ConflictPatch
+
+	for _, c := range cp {
+		value, ok, err := resolver(ctx, c.Path, c.Base, c.Ours, c.Theirs)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			remaining = append(remaining, c)
+			continue
+		}
+
+		resolved = append(resolved, Difference{
+			Path:       c.Path,
+			ChangeType: types.DiffChangeModified,
+			OldValue:   c.Theirs,
+			NewValue:   value,
+		})
+	}
+
+	return resolved, remaining, nil
+}
+
+// Filter returns the subset of cp at or under path, e.g. to resolve a single field
+// inside a conflicted row rather than the whole row.
+func (cp ConflictPatch) Filter(format *types.Format, path types.Path) ConflictPatch {
+	var filtered ConflictPatch
+
+	for _, c := range cp {
+		if len(c.Path) < len(path) {
+			continue
+		}
+
+		match := true
+		for i := range path {
+			if pathPartCompare(format, c.Path[i], path[i]) != 0 {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+func TestConflictPatchSort(t *testing.T) {
+	cp := ConflictPatch{
+		{Path: fp("b"), Base: types.String("b0"), Ours: types.String("b1"), Theirs: types.String("b2")},
+		{Path: fp("a"), Base: types.String("a0"), Ours: types.String("a1"), Theirs: types.String("a2")},
+	}
+
+	cp.Sort(nil)
+
+	if !cp[0].Path.Equals(fp("a")) || !cp[1].Path.Equals(fp("b")) {
+		t.Errorf("expected a before b after Sort, got %+v", cp)
+	}
+}
+
+func TestConflictPatchResolve(t *testing.T) {
+	ctx := context.Background()
+
+	cp := ConflictPatch{
+		{Path: fp("a"), Base: types.String("a0"), Ours: types.String("a1"), Theirs: types.String("a2")},
+		{Path: fp("b"), Base: types.String("b0"), Ours: types.String("b1"), Theirs: types.String("b2")},
+	}
+
+	t.Run("nil resolver leaves everything as a conflict", func(t *testing.T) {
+		resolved, remaining, err := cp.Resolve(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resolved) != 0 {
+			t.Errorf("expected no resolved entries, got %+v", resolved)
+		}
+		if len(remaining) != len(cp) {
+			t.Errorf("expected all %d entries to remain, got %d", len(cp), len(remaining))
+		}
+	})
+
+	t.Run("resolver resolves only the fields it opts into", func(t *testing.T) {
+		resolver := func(ctx context.Context, path types.Path, base, ours, theirs types.Value) (types.Value, bool, error) {
+			if path.Equals(fp("a")) {
+				return types.String("resolved"), true, nil
+			}
+			return nil, false, nil
+		}
+
+		resolved, remaining, err := cp.Resolve(ctx, resolver)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resolved) != 1 || resolved[0].NewValue != types.String("resolved") {
+			t.Errorf("expected one resolved entry with the resolver's value, got %+v", resolved)
+		}
+		if resolved[0].OldValue != types.String("a2") {
+			t.Errorf("expected OldValue to be Theirs, got %v", resolved[0].OldValue)
+		}
+		if len(remaining) != 1 || !remaining[0].Path.Equals(fp("b")) {
+			t.Errorf("expected b's conflict to remain, got %+v", remaining)
+		}
+	})
+
+	t.Run("resolver error is propagated", func(t *testing.T) {
+		wantErr := context.Canceled
+		resolver := func(ctx context.Context, path types.Path, base, ours, theirs types.Value) (types.Value, bool, error) {
+			return nil, false, wantErr
+		}
+
+		if _, _, err := cp.Resolve(ctx, resolver); err != wantErr {
+			t.Errorf("expected resolver error to propagate, got %v", err)
+		}
+	})
+}
+
+func TestConflictPatchFilter(t *testing.T) {
+	cp := ConflictPatch{
+		{Path: fp("a"), Base: types.String("a0")},
+		{Path: fp("b"), Base: types.String("b0")},
+	}
+
+	filtered := cp.Filter(nil, fp("a"))
+
+	if len(filtered) != 1 || !filtered[0].Path.Equals(fp("a")) {
+		t.Errorf("expected only a's conflict, got %+v", filtered)
+	}
+}
@@ -6,6 +6,9 @@ package diff
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"sort"
 
 	"github.com/liquidata-inc/ld/dolt/go/store/types"
 )
@@ -133,3 +136,158 @@ func pathPartCompare(format *types.Format, pp, pp2 types.PathPart) int {
 	}
 	panic("unreachable")
 }
+
+// Invert returns a Patch which, when applied to the RootValue produced by applying p to
+// sourceRoot, reproduces sourceRoot. sourceRoot is required because a forward Patch
+// doesn't always carry the pre-image of a change: a DiffChangeRemoved entry has nothing
+// to put back without looking it up, and a DiffChangeModified entry may have left
+// OldValue unset. vr dereferences any chunk refs that lookup walks through along the
+// way, the same ValueReader a caller would get from ddb.ValueReadWriter(). The returned
+// Patch is in PatchSort order: inverting keeps each change's path, but turns an add into
+// a remove and back, so entries sharing a path are re-sorted by change type.
+func (p Patch) Invert(ctx context.Context, format *types.Format, sourceRoot types.Value, vr types.ValueReader) (Patch, error) {
+	inverted := make(Patch, len(p))
+
+	for i, d := range p {
+		switch d.ChangeType {
+		case types.DiffChangeAdded:
+			inverted[i] = Difference{
+				Path:       d.Path,
+				ChangeType: types.DiffChangeRemoved,
+				OldValue:   d.NewValue,
+			}
+
+		case types.DiffChangeRemoved:
+			oldValue, err := resolvePreImage(ctx, d.Path, sourceRoot, vr)
+			if err != nil {
+				return nil, err
+			}
+
+			inverted[i] = Difference{
+				Path:       d.Path,
+				ChangeType: types.DiffChangeAdded,
+				NewValue:   oldValue,
+			}
+
+		case types.DiffChangeModified:
+			oldValue := d.OldValue
+			if oldValue == nil {
+				var err error
+				oldValue, err = resolvePreImage(ctx, d.Path, sourceRoot, vr)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			inverted[i] = Difference{
+				Path:       d.Path,
+				ChangeType: types.DiffChangeModified,
+				OldValue:   d.NewValue,
+				NewValue:   oldValue,
+			}
+
+		default:
+			return nil, fmt.Errorf("diff: Invert: unknown change type %v at %v", d.ChangeType, d.Path)
+		}
+	}
+
+	sort.Stable(PatchSort{inverted, format})
+
+	return inverted, nil
+}
+
+// resolvePreImage looks up the value at p in root, the pre-image of a change whose
+// forward Patch entry didn't carry enough information to invert on its own. vr resolves
+// any chunk refs encountered while walking down to p, which any persisted RootValue will
+// have.
+func resolvePreImage(ctx context.Context, p types.Path, root types.Value, vr types.ValueReader) (types.Value, error) {
+	v, err := p.Resolve(ctx, root, vr)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, fmt.Errorf("diff: could not resolve path %v against source root", p)
+	}
+
+	return v, nil
+}
+
+// Compose folds two sequential patches into one: applying Compose(format, a, b) to a
+// root has the same effect as applying a and then applying b to the result. a and b
+// must each be sorted in Patch's standard order (as PatchSort, Invert, and the diff
+// algorithms that build a Patch all produce), which lets Compose co-walk both patches in
+// a single O(n+m) pass using pathPartCompare rather than re-diffing the full
+// intermediate RootValue.
+func Compose(format *types.Format, a, b Patch) (Patch, error) {
+	var composed Patch
+
+	ia, ib := 0, 0
+
+	for ia < len(a) || ib < len(b) {
+		switch {
+		case ib >= len(b) || (ia < len(a) && pathIsLess(format, a[ia].Path, b[ib].Path)):
+			composed = append(composed, a[ia])
+			ia++
+
+		case ia >= len(a) || (ib < len(b) && pathIsLess(format, b[ib].Path, a[ia].Path)):
+			composed = append(composed, b[ib])
+			ib++
+
+		default:
+			d, keep, err := composeDifference(a[ia], b[ib])
+			if err != nil {
+				return nil, err
+			}
+
+			if keep {
+				composed = append(composed, d)
+			}
+
+			ia++
+			ib++
+		}
+	}
+
+	return composed, nil
+}
+
+// composeDifference combines two Differences at the same Path, where a is applied
+// before b. It returns keep=false when the pair cancels out entirely (an add
+// immediately undone by a remove).
+func composeDifference(a, b Difference) (Difference, bool, error) {
+	switch a.ChangeType {
+	case types.DiffChangeAdded:
+		switch b.ChangeType {
+		case types.DiffChangeRemoved:
+			// added by a, removed by b: net no-op.
+			return Difference{}, false, nil
+		case types.DiffChangeModified, types.DiffChangeAdded:
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeAdded, NewValue: b.NewValue}, true, nil
+		}
+
+	case types.DiffChangeModified:
+		switch b.ChangeType {
+		case types.DiffChangeModified:
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeModified, OldValue: a.OldValue, NewValue: b.NewValue}, true, nil
+		case types.DiffChangeRemoved:
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeRemoved, OldValue: a.OldValue}, true, nil
+		case types.DiffChangeAdded:
+			// modified by a, re-added by b: same net effect as a further modification.
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeModified, OldValue: a.OldValue, NewValue: b.NewValue}, true, nil
+		}
+
+	case types.DiffChangeRemoved:
+		switch b.ChangeType {
+		case types.DiffChangeAdded:
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeModified, OldValue: a.OldValue, NewValue: b.NewValue}, true, nil
+		case types.DiffChangeModified:
+			// removed by a, so b.ChangeType of Modified means b re-created the path.
+			return Difference{Path: a.Path, ChangeType: types.DiffChangeAdded, NewValue: b.NewValue}, true, nil
+		case types.DiffChangeRemoved:
+			return a, true, nil
+		}
+	}
+
+	return Difference{}, false, fmt.Errorf("diff: Compose: cannot combine change types %v and %v at %v", a.ChangeType, b.ChangeType, a.Path)
+}
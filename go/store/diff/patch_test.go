@@ -0,0 +1,192 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/liquidata-inc/ld/dolt/go/store/types"
+)
+
+func fp(name string) types.Path {
+	return types.Path{types.FieldPath{Name: name}}
+}
+
+func TestPatchInvert(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("added becomes removed", func(t *testing.T) {
+		p := Patch{{Path: fp("a"), ChangeType: types.DiffChangeAdded, NewValue: types.String("new")}}
+
+		inverted, err := p.Invert(ctx, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(inverted) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(inverted))
+		}
+		if inverted[0].ChangeType != types.DiffChangeRemoved {
+			t.Errorf("expected DiffChangeRemoved, got %v", inverted[0].ChangeType)
+		}
+		if inverted[0].OldValue != types.String("new") {
+			t.Errorf("expected OldValue %v, got %v", types.String("new"), inverted[0].OldValue)
+		}
+	})
+
+	t.Run("modified with OldValue already present swaps old and new", func(t *testing.T) {
+		p := Patch{{
+			Path:       fp("a"),
+			ChangeType: types.DiffChangeModified,
+			OldValue:   types.String("before"),
+			NewValue:   types.String("after"),
+		}}
+
+		inverted, err := p.Invert(ctx, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if inverted[0].ChangeType != types.DiffChangeModified {
+			t.Errorf("expected DiffChangeModified, got %v", inverted[0].ChangeType)
+		}
+		if inverted[0].OldValue != types.String("after") || inverted[0].NewValue != types.String("before") {
+			t.Errorf("expected old/new swapped, got OldValue=%v NewValue=%v", inverted[0].OldValue, inverted[0].NewValue)
+		}
+	})
+
+	t.Run("unknown change type is an error", func(t *testing.T) {
+		p := Patch{{Path: fp("a"), ChangeType: types.DiffChangeType(99)}}
+
+		if _, err := p.Invert(ctx, nil, nil, nil); err == nil {
+			t.Error("expected an error for an unrecognized change type")
+		}
+	})
+
+	// Invert's DiffChangeRemoved case, and the DiffChangeModified case where OldValue was
+	// left unset, both call resolvePreImage, which walks sourceRoot with a real
+	// types.Value/ValueReader. This package has no fixtures for building a RootValue, so
+	// that path is not exercised here.
+}
+
+func TestPatchCompose(t *testing.T) {
+	a := fp("a")
+	b := fp("b")
+
+	tests := []struct {
+		name     string
+		a        Difference
+		b        Difference
+		wantKeep bool
+		want     Difference
+	}{
+		{
+			name:     "added then removed cancels out",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v1")},
+			wantKeep: false,
+		},
+		{
+			name:     "added then modified is added with final value",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v1"), NewValue: types.String("v2")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v2")},
+		},
+		{
+			name:     "added then added keeps final value",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v2")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v2")},
+		},
+		{
+			name:     "modified then modified collapses to one modify",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v1"), NewValue: types.String("v2")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v2")},
+		},
+		{
+			name:     "modified then removed is removed with original pre-image",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v1")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v0")},
+		},
+		{
+			name:     "modified then added is a further modify",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v1")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v2")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v2")},
+		},
+		{
+			name:     "removed then added is a modify",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v0")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v1")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeModified, OldValue: types.String("v0"), NewValue: types.String("v1")},
+		},
+		{
+			name:     "removed then modified re-creates the path",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v0")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeModified, NewValue: types.String("v1")},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("v1")},
+		},
+		{
+			name:     "removed then removed stays removed",
+			a:        Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v0")},
+			b:        Difference{Path: a, ChangeType: types.DiffChangeRemoved},
+			wantKeep: true,
+			want:     Difference{Path: a, ChangeType: types.DiffChangeRemoved, OldValue: types.String("v0")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, keep, err := composeDifference(test.a, test.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keep != test.wantKeep {
+				t.Fatalf("expected keep=%v, got %v", test.wantKeep, keep)
+			}
+			if !keep {
+				return
+			}
+			if got.ChangeType != test.want.ChangeType || got.OldValue != test.want.OldValue || got.NewValue != test.want.NewValue {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+
+	t.Run("Compose co-walks two patches by path", func(t *testing.T) {
+		patchA := Patch{
+			{Path: a, ChangeType: types.DiffChangeAdded, NewValue: types.String("a1")},
+			{Path: b, ChangeType: types.DiffChangeModified, OldValue: types.String("b0"), NewValue: types.String("b1")},
+		}
+		patchB := Patch{
+			{Path: b, ChangeType: types.DiffChangeModified, OldValue: types.String("b1"), NewValue: types.String("b2")},
+		}
+
+		composed, err := Compose(nil, patchA, patchB)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(composed) != 2 {
+			t.Fatalf("expected 2 entries, got %d: %+v", len(composed), composed)
+		}
+		if composed[0].ChangeType != types.DiffChangeAdded || composed[0].NewValue != types.String("a1") {
+			t.Errorf("expected a's untouched add to pass through, got %+v", composed[0])
+		}
+		if composed[1].NewValue != types.String("b2") || composed[1].OldValue != types.String("b0") {
+			t.Errorf("expected b's two modifies collapsed into one, got %+v", composed[1])
+		}
+	})
+}